@@ -0,0 +1,44 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// groupAccessTokenScopes are the scopes GitLab accepts for group access
+// tokens. This is a narrower set than project access token scopes, so it
+// is validated separately rather than folded into validateScopes.
+var groupAccessTokenScopes = map[string]bool{
+	"api":              true,
+	"read_api":         true,
+	"read_registry":    true,
+	"write_registry":   true,
+	"read_repository":  true,
+	"write_repository": true,
+	"create_runner":    true,
+}
+
+func validateGroupScopes(scopes []string) error {
+	var err *multierror.Error
+	for _, s := range scopes {
+		if !groupAccessTokenScopes[s] {
+			err = multierror.Append(err, fmt.Errorf("invalid group access token scope: %s", s))
+		}
+	}
+	return err.ErrorOrNil()
+}
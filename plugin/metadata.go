@@ -0,0 +1,68 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// auditMetadataLabels maps a GitLab object ID to the key a token's kind
+// should report it under. Kept separate from auditMetadata so it's
+// testable without a GitLab client or a running backend.
+func auditMetadataLabels(kind TokenKind, id int) map[string]string {
+	if kind == TokenKindGroup {
+		return map[string]string{"group_id": strconv.Itoa(id)}
+	}
+	return map[string]string{"project_id": strconv.Itoa(id)}
+}
+
+// auditMetadata builds the "metadata" entry attached to a token creation
+// response's Data, so Vault's audit log carries enough structured
+// context (which GitLab project/group, which Vault entity, which role,
+// which admin PAT) to correlate a Vault request with the GitLab activity
+// it produced - without anyone having to join on the token value itself.
+func (b *GitlabBackend) auditMetadata(ctx context.Context, req *logical.Request, kind TokenKind, id int, roleName string) map[string]string {
+	metadata := auditMetadataLabels(kind, id)
+	if roleName != "" {
+		metadata["role_name"] = roleName
+	}
+	if req.DisplayName != "" {
+		metadata["requesting_entity"] = req.DisplayName
+	}
+
+	gc, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return metadata
+	}
+
+	switch kind {
+	case TokenKindGroup:
+		if path, err := gc.GroupPath(id); err == nil {
+			metadata["group_path"] = path
+		}
+	default:
+		if path, err := gc.ProjectPath(id); err == nil {
+			metadata["project_path"] = path
+		}
+	}
+	if adminUserID, err := gc.CurrentUserID(); err == nil {
+		metadata["gitlab_admin_user_id"] = strconv.Itoa(adminUserID)
+	}
+
+	return metadata
+}
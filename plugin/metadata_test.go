@@ -0,0 +1,39 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import "testing"
+
+func TestAuditMetadataLabels_Project(t *testing.T) {
+	labels := auditMetadataLabels(TokenKindProject, 42)
+
+	if got, want := labels["project_id"], "42"; got != want {
+		t.Fatalf("project_id = %q, want %q", got, want)
+	}
+	if _, ok := labels["group_id"]; ok {
+		t.Fatal("expected no group_id label for a project token")
+	}
+}
+
+func TestAuditMetadataLabels_Group(t *testing.T) {
+	labels := auditMetadataLabels(TokenKindGroup, 7)
+
+	if got, want := labels["group_id"], "7"; got != want {
+		t.Fatalf("group_id = %q, want %q", got, want)
+	}
+	if _, ok := labels["project_id"]; ok {
+		t.Fatal("expected no project_id label for a group token")
+	}
+}
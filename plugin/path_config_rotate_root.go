@@ -0,0 +1,131 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const pathPatternConfigRotateRoot = "config/rotate-root"
+
+// rotateRootClient is the subset of the GitLab client rotateRoot needs,
+// declared locally so the mint/store/revoke ordering can be unit tested
+// without depending on the full GitLab client.
+type rotateRootClient interface {
+	CurrentUserID() (int, error)
+	CreateRootToken(userID int, name string, expiresAt *time.Time) (*PAT, error)
+	RevokePersonalAccessToken(id int) error
+}
+
+// rotateRoot mints a replacement admin PAT and hands it to store before
+// revoking oldTokenID, so a store failure - e.g. storeConfig unable to
+// write to Vault's storage backend - leaves the old, still-valid token as
+// the mount's configured credential instead of leaving the mount with
+// none at all. A failure to revoke the old token afterwards does not fail
+// the rotation, since the mount already has a working credential at that
+// point; onRevokeErr is called so the caller can log it rather than just
+// swallowing it silently.
+func rotateRoot(gc rotateRootClient, oldTokenID int, store func(newToken *PAT) error, onRevokeErr func(tokenID int, err error)) (*PAT, error) {
+	adminUserID, err := gc.CurrentUserID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the current token's owner: %w", err)
+	}
+
+	newToken, err := gc.CreateRootToken(adminUserID, "vault-root-token", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mint a replacement GitLab token: %w", err)
+	}
+
+	if err := store(newToken); err != nil {
+		return nil, fmt.Errorf("GitLab minted a replacement token but it could not be persisted; the old token was left untouched and the mount is still usable: %w", err)
+	}
+
+	if oldTokenID > 0 {
+		if err := gc.RevokePersonalAccessToken(oldTokenID); err != nil && onRevokeErr != nil {
+			onRevokeErr(oldTokenID, err)
+		}
+	}
+
+	return newToken, nil
+}
+
+// pathConfigRotateRootWrite rotates the admin PAT the mount is configured
+// with, following the same config/rotate-root convention used by the
+// database and cloud secret engines. See rotateRoot for the mint-then-
+// store-then-revoke ordering that keeps this safe.
+func (b *GitlabBackend) pathConfigRotateRootWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getConfig(ctx, req.Storage)
+	if err != nil {
+		return logical.ErrorResponse("failed to obtain GitLab config - %s", err.Error()), nil
+	}
+	if config == nil {
+		return logical.ErrorResponse("GitLab backend configuration has not been set up"), nil
+	}
+
+	gc, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return logical.ErrorResponse("failed to obtain gitlab client - %s", err.Error()), nil
+	}
+
+	newToken, err := rotateRoot(gc, config.TokenID, func(newToken *PAT) error {
+		config.Token = newToken.Token
+		config.TokenID = newToken.ID
+		return storeConfig(ctx, req.Storage, config)
+	}, func(tokenID int, err error) {
+		b.Logger().Warn("rotated GitLab admin token but failed to revoke the previous one", "token_id", tokenID, "error", err)
+	})
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	b.reset()
+
+	return &logical.Response{Data: map[string]interface{}{
+		"id":         newToken.ID,
+		"expires_at": newToken.ExpiresAt,
+	}}, nil
+}
+
+func pathConfigRotateRoot(b *GitlabBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: pathPatternConfigRotateRoot,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathConfigRotateRootWrite,
+					Summary:  "Rotate the GitLab admin token the mount is configured with",
+				},
+			},
+			HelpSynopsis:    pathConfigRotateRootHelpSyn,
+			HelpDescription: pathConfigRotateRootHelpDesc,
+		},
+	}
+}
+
+const pathConfigRotateRootHelpSyn = `Rotate the GitLab admin token used by this mount.`
+const pathConfigRotateRootHelpDesc = `
+This path rotates the GitLab personal access token configured for this mount. A
+replacement token is minted via GitLab's users API without revoking the current one,
+and is only written to Vault storage after a successful mint; the old token is revoked
+only once that write has succeeded. This ordering means a storage failure mid-rotation
+never leaves the mount without a working credential - worst case, the newly minted
+token is left unused but still valid in GitLab alongside the old one; a later rotation
+only revokes the token stored in the mount's config, not that unused one.
+`
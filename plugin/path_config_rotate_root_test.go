@@ -0,0 +1,110 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeRotateRootClient struct {
+	userID        int
+	minted        *PAT
+	revokedTokens []int
+	revokeErr     error
+}
+
+func (f *fakeRotateRootClient) CurrentUserID() (int, error) {
+	return f.userID, nil
+}
+
+func (f *fakeRotateRootClient) CreateRootToken(userID int, name string, expiresAt *time.Time) (*PAT, error) {
+	return f.minted, nil
+}
+
+func (f *fakeRotateRootClient) RevokePersonalAccessToken(id int) error {
+	f.revokedTokens = append(f.revokedTokens, id)
+	return f.revokeErr
+}
+
+func TestRotateRoot_RevokesOldTokenOnlyAfterStoreSucceeds(t *testing.T) {
+	gc := &fakeRotateRootClient{userID: 1, minted: &PAT{ID: 2, Token: "new-token"}}
+
+	var storeCalledBeforeRevoke bool
+	_, err := rotateRoot(gc, 99, func(newToken *PAT) error {
+		storeCalledBeforeRevoke = len(gc.revokedTokens) == 0
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !storeCalledBeforeRevoke {
+		t.Fatal("expected store to be called before the old token was revoked")
+	}
+	if len(gc.revokedTokens) != 1 || gc.revokedTokens[0] != 99 {
+		t.Fatalf("expected old token 99 to be revoked exactly once, got %v", gc.revokedTokens)
+	}
+}
+
+func TestRotateRoot_StoreFailureLeavesOldTokenUnrevoked(t *testing.T) {
+	gc := &fakeRotateRootClient{userID: 1, minted: &PAT{ID: 2, Token: "new-token"}}
+	storeErr := errors.New("storage write failed")
+
+	_, err := rotateRoot(gc, 99, func(newToken *PAT) error {
+		return storeErr
+	}, nil)
+	if err == nil {
+		t.Fatal("expected an error when store fails")
+	}
+
+	if len(gc.revokedTokens) != 0 {
+		t.Fatalf("expected the old token to be left alone when store fails, but revoke was called: %v", gc.revokedTokens)
+	}
+}
+
+func TestRotateRoot_RevokeFailureDoesNotFailRotation(t *testing.T) {
+	gc := &fakeRotateRootClient{userID: 1, minted: &PAT{ID: 2, Token: "new-token"}, revokeErr: errors.New("revoke failed")}
+
+	var reportedTokenID int
+	var reportedErr error
+	newToken, err := rotateRoot(gc, 99, func(newToken *PAT) error {
+		return nil
+	}, func(tokenID int, err error) {
+		reportedTokenID = tokenID
+		reportedErr = err
+	})
+	if err != nil {
+		t.Fatalf("expected rotation to succeed even though revoking the old token failed, got: %v", err)
+	}
+	if newToken == nil || newToken.ID != 2 {
+		t.Fatalf("expected the new token to be returned, got %v", newToken)
+	}
+	if reportedTokenID != 99 || reportedErr == nil {
+		t.Fatalf("expected the revoke failure to be reported via onRevokeErr, got tokenID=%d err=%v", reportedTokenID, reportedErr)
+	}
+}
+
+func TestRotateRoot_SkipsRevokeWhenNoOldTokenIDIsKnown(t *testing.T) {
+	gc := &fakeRotateRootClient{userID: 1, minted: &PAT{ID: 2, Token: "new-token"}}
+
+	if _, err := rotateRoot(gc, 0, func(newToken *PAT) error { return nil }, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gc.revokedTokens) != 0 {
+		t.Fatalf("expected no revoke call when oldTokenID is unknown, got %v", gc.revokedTokens)
+	}
+}
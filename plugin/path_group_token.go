@@ -0,0 +1,130 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const pathPatternGroupToken = "group/token"
+
+// groupAccessTokenSchema mirrors accessTokenSchema; "id" here refers to
+// the group ID rather than a project ID, and there is no "role" field.
+var groupAccessTokenSchema = map[string]*framework.FieldSchema{
+	"id": {
+		Type:        framework.TypeInt,
+		Description: "Group ID to create a group access token for",
+	},
+	"name": {
+		Type:        framework.TypeString,
+		Description: "The name of the group access token",
+	},
+	"scopes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "List of scopes",
+	},
+	"expires_at": {
+		Type:        framework.TypeTime,
+		Description: "The token expires at midnight UTC on that date",
+	},
+	"access_level": {
+		Type:        framework.TypeInt,
+		Description: "access level of group access token",
+	},
+}
+
+func (b *GitlabBackend) pathGroupTokenCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	gc, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return logical.ErrorResponse("failed to obtain gitlab client - %s", err.Error()), nil
+	}
+
+	var tokenStorage TokenStorageEntry
+	tokenStorage.retrieve(data)
+	tokenStorage.BaseTokenStorage.Kind = TokenKindGroup
+
+	config, err := getConfig(ctx, req.Storage)
+	if err != nil {
+		return logical.ErrorResponse("failed to obtain GitLab config - %s", err.Error()), nil
+	}
+	if config == nil {
+		return logical.ErrorResponse("GitLab backend configuration has not been set up"), nil
+	}
+	if err := tokenStorage.assertValid(config.MaxTTL, config.AllowOwnerLevel); err != nil {
+		return logical.ErrorResponse("Failed to validate - " + err.Error()), nil
+	}
+
+	b.Logger().Debug("generating group access token", "id", tokenStorage.BaseTokenStorage.ID,
+		"name", tokenStorage.BaseTokenStorage.Name, "scopes", tokenStorage.BaseTokenStorage.Scopes)
+	pat, err := gc.CreateGroupAccessToken(&tokenStorage.BaseTokenStorage, tokenStorage.ExpiresAt)
+	if err != nil {
+		return logical.ErrorResponse("Failed to create a token - " + err.Error()), nil
+	}
+
+	tokenData := tokenDetails(pat)
+	tokenData["metadata"] = b.auditMetadata(ctx, req, TokenKindGroup, tokenStorage.BaseTokenStorage.ID, tokenStorage.RoleName)
+
+	resp := b.Secret(secretAccessTokenType).Response(tokenData, map[string]interface{}{
+		secretTokenIDKey:   pat.ID,
+		secretGroupIDKey:   tokenStorage.BaseTokenStorage.ID,
+		secretTokenKindKey: TokenKindGroup,
+		secretExpiresAtKey: patExpiresAt(pat),
+	})
+	// Ad-hoc tokens aren't tied to a role, so there is no TTL policy to
+	// renew against - only revoke on lease expiry/explicit revocation.
+	resp.Secret.Renewable = false
+	return resp, nil
+}
+
+func pathGroupToken(b *GitlabBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: pathPatternGroupToken,
+			Fields:  groupAccessTokenSchema,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathGroupTokenCreate,
+					Summary:  "Create a group access token",
+					Examples: groupTokenExamples,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathGroupTokenCreate,
+				},
+			},
+			HelpSynopsis:    pathGroupTokenHelpSyn,
+			HelpDescription: pathGroupTokenHelpDesc,
+		},
+	}
+}
+
+const pathGroupTokenHelpSyn = `Generate a group access token for a given group with token name, scopes.`
+const pathGroupTokenHelpDesc = `
+This path allows you to generate a group access token. You must supply a group id to generate a token for, a name, which
+will be used as a name field in Gitlab, and scopes for the generated group access token.
+`
+
+var groupTokenExamples = []framework.RequestExample{
+	{
+		Description: "Create a group access token",
+		Data: map[string]interface{}{
+			"id":     1,
+			"name":   "MyGroupAccessToken",
+			"scopes": []string{"read_api", "read_repository"},
+		},
+	},
+}
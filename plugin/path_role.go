@@ -0,0 +1,199 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const pathPatternRole = "roles/" + framework.GenericNameRegex("name")
+const pathPatternRoleList = "roles/?$"
+
+func roleStoragePath(name string) string {
+	return "roles/" + name
+}
+
+func (b *GitlabBackend) roleEntry(ctx context.Context, s logical.Storage, name string) (*roleStorageEntry, error) {
+	entry, err := s.Get(ctx, roleStoragePath(name))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role roleStorageEntry
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (b *GitlabBackend) pathRoleExistenceCheck() framework.ExistenceFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+		role, err := b.roleEntry(ctx, req.Storage, data.Get("name").(string))
+		if err != nil {
+			return false, err
+		}
+		return role != nil, nil
+	}
+}
+
+func (b *GitlabBackend) pathRoleRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, err := b.roleEntry(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{Data: role.toResponseData()}, nil
+}
+
+func (b *GitlabBackend) pathRoleWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("missing role name"), nil
+	}
+
+	role, err := b.roleEntry(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleStorageEntry{}
+	}
+
+	if projectIDRaw, ok := data.GetOk("project_id"); ok {
+		role.ProjectID = projectIDRaw.(int)
+	}
+	if scopesRaw, ok := data.GetOk("default_scopes"); ok {
+		role.DefaultScopes = scopesRaw.([]string)
+	}
+	if accessLevelRaw, ok := data.GetOk("default_access_level"); ok {
+		role.DefaultAccessLevel = accessLevelRaw.(int)
+	}
+	if allowedRaw, ok := data.GetOk("allowed_scopes"); ok {
+		role.AllowedScopes = allowedRaw.([]string)
+	}
+	if deniedRaw, ok := data.GetOk("denied_scopes"); ok {
+		role.DeniedScopes = deniedRaw.([]string)
+	}
+	if maxAccessLevelRaw, ok := data.GetOk("max_access_level"); ok {
+		role.MaxAccessLevel = maxAccessLevelRaw.(int)
+	}
+	if maxTokensRaw, ok := data.GetOk("max_tokens_per_period"); ok {
+		role.MaxTokensPerPeriod = maxTokensRaw.(int)
+	}
+
+	if err := role.ParseTokenFields(req, data); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	if role.ProjectID <= 0 {
+		return logical.ErrorResponse("project_id is empty or invalid"), nil
+	}
+	if role.TokenTTL <= 0 {
+		return logical.ErrorResponse("token_ttl must be set to a positive duration"), nil
+	}
+	if len(role.DefaultScopes) > 0 {
+		if err := validateScopes(role.DefaultScopes); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+	if err := role.validateDefaults(); err != nil {
+		return logical.ErrorResponse("role's own defaults don't satisfy its constraints - " + err.Error()), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(roleStoragePath(name), role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *GitlabBackend) pathRoleDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if err := req.Storage.Delete(ctx, roleStoragePath(name)); err != nil {
+		return nil, fmt.Errorf("error deleting role %q: %w", name, err)
+	}
+	return nil, nil
+}
+
+func (b *GitlabBackend) pathRoleList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List(ctx, "roles/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func pathRole(b *GitlabBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern:        pathPatternRole,
+			Fields:         roleSchema,
+			ExistenceCheck: b.pathRoleExistenceCheck(),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathRoleRead,
+					Summary:  "Read a role",
+				},
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathRoleWrite,
+					Summary:  "Create a role",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathRoleWrite,
+					Summary:  "Update a role",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.pathRoleDelete,
+					Summary:  "Delete a role",
+				},
+			},
+			HelpSynopsis:    pathRoleHelpSyn,
+			HelpDescription: pathRoleHelpDesc,
+		},
+		{
+			Pattern: pathPatternRoleList,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathRoleList,
+					Summary:  "List the existing roles",
+				},
+			},
+			HelpSynopsis:    pathRoleHelpSyn,
+			HelpDescription: pathRoleHelpDesc,
+		},
+	}
+}
+
+const pathRoleHelpSyn = `Manage the roles that can be used to generate project access tokens.`
+const pathRoleHelpDesc = `
+This path lets you manage the roles that can be used to generate project access tokens.
+A role binds a project id, scopes and an access level, along with a token_ttl and
+token_max_ttl, so tokens can be requested through token/<role name> and get a proper
+Vault lease instead of being handed back bare.
+`
@@ -45,6 +45,10 @@ var accessTokenSchema = map[string]*framework.FieldSchema{
 		Type:        framework.TypeInt,
 		Description: "access level of project access token",
 	},
+	"role": {
+		Type:        framework.TypeString,
+		Description: "Optional role to bind this request to. If set, scopes/access_level default to and are constrained by the role's allowed_scopes, denied_scopes and max_access_level",
+	},
 }
 
 func tokenDetails(pat *PAT) map[string]interface{} {
@@ -61,7 +65,18 @@ func tokenDetails(pat *PAT) map[string]interface{} {
 	return d
 }
 
-func (b *GitlabBackend) pathTokenCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+// patExpiresAt renders the real expires_at GitLab set on a minted token as
+// RFC3339, for storage in a secret's internal data - internal data is
+// JSON round-tripped, so it's stored and read back as a string rather
+// than a time.Time. Empty if GitLab didn't return one.
+func patExpiresAt(pat *PAT) string {
+	if pat.ExpiresAt == nil {
+		return ""
+	}
+	return time.Time(*pat.ExpiresAt).Format(time.RFC3339)
+}
+
+func (b *GitlabBackend) pathTokenCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (resp *logical.Response, err error) {
 	gc, err := b.getClient(ctx, req.Storage)
 	if err != nil {
 		return logical.ErrorResponse("failed to obtain gitlab client - %s", err.Error()), nil
@@ -70,6 +85,22 @@ func (b *GitlabBackend) pathTokenCreate(ctx context.Context, req *logical.Reques
 	var tokenStorage TokenStorageEntry
 	tokenStorage.retrieve(data)
 
+	var role *roleStorageEntry
+	if roleNameRaw, ok := data.GetOk("role"); ok && roleNameRaw.(string) != "" {
+		roleName := roleNameRaw.(string)
+		role, err = b.roleEntry(ctx, req.Storage, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse("role %q does not exist", roleName), nil
+		}
+		if err := role.constrain(&tokenStorage.BaseTokenStorage); err != nil {
+			return logical.ErrorResponse("Failed to validate against role - " + err.Error()), nil
+		}
+		tokenStorage.RoleName = roleName
+	}
+
 	config, err := getConfig(ctx, req.Storage)
 	if err != nil {
 		return logical.ErrorResponse("failed to obtain GitLab config - %s", err.Error()), nil
@@ -77,18 +108,35 @@ func (b *GitlabBackend) pathTokenCreate(ctx context.Context, req *logical.Reques
 	if config == nil {
 		return logical.ErrorResponse("GitLab backend configuration has not been set up"), nil
 	}
-	err = tokenStorage.assertValid(config.MaxTTL, config.AllowOwnerLevel)
-	if err != nil {
+	if err := tokenStorage.assertValid(config.MaxTTL, config.AllowOwnerLevel); err != nil {
 		return logical.ErrorResponse("Failed to validate - " + err.Error()), nil
 	}
 
+	// Reserve a quota slot only once the request is otherwise known-good,
+	// and refund it below if token issuance still doesn't pan out - a
+	// bad request or a transient GitLab error shouldn't cost the caller
+	// part of their max_tokens_per_period.
+	if role != nil {
+		if err := checkAndIncrementQuota(ctx, req.Storage, tokenStorage.RoleName, role); err != nil {
+			return nil, logical.CodedError(429, err.Error())
+		}
+		defer func() {
+			if err != nil || (resp != nil && resp.IsError()) {
+				refundQuota(ctx, req.Storage, tokenStorage.RoleName, role)
+			}
+		}()
+	}
+
 	b.Logger().Debug("generating access token", "id", tokenStorage.BaseTokenStorage.ID,
 		"name", tokenStorage.BaseTokenStorage.Name, "scopes", tokenStorage.BaseTokenStorage.Scopes)
 	pat, err := gc.CreateProjectAccessToken(&tokenStorage.BaseTokenStorage, tokenStorage.ExpiresAt)
 	if err != nil {
 		return logical.ErrorResponse("Failed to create a token - " + err.Error()), nil
 	}
-	return &logical.Response{Data: tokenDetails(pat)}, nil
+
+	respData := tokenDetails(pat)
+	respData["metadata"] = b.auditMetadata(ctx, req, TokenKindProject, tokenStorage.BaseTokenStorage.ID, tokenStorage.RoleName)
+	return &logical.Response{Data: respData}, nil
 }
 
 // There is a correctness check that verifies there is an ExistenceFunc for all
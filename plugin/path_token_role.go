@@ -0,0 +1,157 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const pathPatternTokenRole = "token/" + framework.GenericNameRegex("name")
+
+var tokenRoleSchema = map[string]*framework.FieldSchema{
+	"name": {
+		Type:        framework.TypeString,
+		Description: "Name of the role to create a token for",
+	},
+	"scopes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "List of scopes for the token. Defaults to the role's default_scopes; must satisfy allowed_scopes/denied_scopes",
+	},
+	"access_level": {
+		Type:        framework.TypeInt,
+		Description: "Access level for the token. Defaults to the role's default_access_level; must satisfy max_access_level",
+	},
+}
+
+func (b *GitlabBackend) pathTokenRoleCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (resp *logical.Response, err error) {
+	roleName := data.Get("name").(string)
+
+	role, err := b.roleEntry(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role %q does not exist", roleName), nil
+	}
+	if role.TokenTTL <= 0 {
+		return logical.ErrorResponse("role %q has no token_ttl configured", roleName), nil
+	}
+
+	gc, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return logical.ErrorResponse("failed to obtain gitlab client - %s", err.Error()), nil
+	}
+
+	config, err := getConfig(ctx, req.Storage)
+	if err != nil {
+		return logical.ErrorResponse("failed to obtain GitLab config - %s", err.Error()), nil
+	}
+	if config == nil {
+		return logical.ErrorResponse("GitLab backend configuration has not been set up"), nil
+	}
+	if config.MaxTTL > 0 && role.TokenTTL > config.MaxTTL {
+		return logical.ErrorResponse("role %q token_ttl of %s exceeds the mount's configured max_ttl of %s", roleName, role.TokenTTL, config.MaxTTL), nil
+	}
+	if config.MaxTTL > 0 && role.TokenMaxTTL > config.MaxTTL {
+		return logical.ErrorResponse("role %q token_max_ttl of %s exceeds the mount's configured max_ttl of %s", roleName, role.TokenMaxTTL, config.MaxTTL), nil
+	}
+
+	base := BaseTokenStorageEntry{
+		ID:   role.ProjectID,
+		Name: fmt.Sprintf("vault-%s-%d", roleName, time.Now().Unix()),
+	}
+	if scopesRaw, ok := data.GetOk("scopes"); ok {
+		base.Scopes = scopesRaw.([]string)
+	}
+	if accessLevelRaw, ok := data.GetOk("access_level"); ok {
+		base.AccessLevel = accessLevelRaw.(int)
+	}
+
+	if err := role.constrain(&base); err != nil {
+		return logical.ErrorResponse("Failed to validate against role - " + err.Error()), nil
+	}
+	if err := base.assertValid(config.AllowOwnerLevel); err != nil {
+		return logical.ErrorResponse("Failed to validate - " + err.Error()), nil
+	}
+
+	// Reserve a quota slot only once the request is otherwise known-good,
+	// and refund it below if token issuance still doesn't pan out - a
+	// bad request or a transient GitLab error shouldn't cost the caller
+	// part of their max_tokens_per_period.
+	if err := checkAndIncrementQuota(ctx, req.Storage, roleName, role); err != nil {
+		return nil, logical.CodedError(429, err.Error())
+	}
+	defer func() {
+		if err != nil || (resp != nil && resp.IsError()) {
+			refundQuota(ctx, req.Storage, roleName, role)
+		}
+	}()
+
+	expiresAt := time.Now().UTC().Add(role.TokenTTL)
+	pat, err := gc.CreateProjectAccessToken(&base, &expiresAt)
+	if err != nil {
+		return logical.ErrorResponse("Failed to create a token - " + err.Error()), nil
+	}
+
+	tokenData := tokenDetails(pat)
+	tokenData["metadata"] = b.auditMetadata(ctx, req, TokenKindProject, role.ProjectID, roleName)
+
+	resp = b.Secret(secretAccessTokenType).Response(tokenData, map[string]interface{}{
+		secretTokenIDKey:   pat.ID,
+		secretProjectIDKey: role.ProjectID,
+		secretRoleNameKey:  roleName,
+		secretExpiresAtKey: patExpiresAt(pat),
+	})
+	resp.Secret.TTL = role.TokenTTL
+	resp.Secret.MaxTTL = role.TokenMaxTTL
+	resp.Secret.Renewable = role.TokenMaxTTL > role.TokenTTL
+
+	return resp, nil
+}
+
+func pathTokenRole(b *GitlabBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: pathPatternTokenRole,
+			Fields:  tokenRoleSchema,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathTokenRoleCreate,
+					Summary:  "Request a project access token for a role",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathTokenRoleCreate,
+					Summary:  "Request a project access token for a role, optionally overriding scopes/access_level within the role's limits",
+				},
+			},
+			HelpSynopsis:    pathTokenRoleHelpSyn,
+			HelpDescription: pathTokenRoleHelpDesc,
+		},
+	}
+}
+
+const pathTokenRoleHelpSyn = `Generate a project access token using a pre-configured role.`
+const pathTokenRoleHelpDesc = `
+This path generates a project access token using the project id configured on the named
+role. scopes/access_level default to the role's default_scopes/default_access_level and,
+if supplied, must satisfy the role's allowed_scopes, denied_scopes and max_access_level.
+The returned token is registered as a Vault lease and is revoked automatically on expiry
+or on 'vault lease revoke'.
+`
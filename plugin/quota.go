@@ -0,0 +1,127 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// quotaPeriod is the fixed window a roles/<name>/usage/<period> counter
+// is bucketed by. There's no background sweep to reset counters, so a
+// fixed, unix-time-derived bucket key is what starts each period with a
+// fresh count: once a bucket's window passes, its counter is simply never
+// read again. Past buckets are not deleted from storage.
+const quotaPeriod = time.Hour
+
+// quotaLocks serializes checkAndIncrementQuota/refundQuota per role so
+// concurrent requests against the same role can't both observe
+// usage.Count below the limit and both proceed - the classic
+// Get-then-Put race. Sized and keyed the same way locksutil is used
+// elsewhere in Vault's built-in secrets engines for per-name locking.
+var quotaLocks = locksutil.CreateLocks()
+
+func quotaBucket(t time.Time) string {
+	return fmt.Sprintf("%d", t.Unix()/int64(quotaPeriod.Seconds()))
+}
+
+func roleUsagePath(roleName, bucket string) string {
+	return fmt.Sprintf("roles/%s/usage/%s", roleName, bucket)
+}
+
+type roleUsageEntry struct {
+	Count int `json:"count"`
+}
+
+func getRoleUsage(ctx context.Context, storage logical.Storage, path string) (*roleUsageEntry, error) {
+	var usage roleUsageEntry
+	entry, err := storage.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if err := entry.DecodeJSON(&usage); err != nil {
+			return nil, err
+		}
+	}
+	return &usage, nil
+}
+
+func putRoleUsage(ctx context.Context, storage logical.Storage, path string, usage *roleUsageEntry) error {
+	entry, err := logical.StorageEntryJSON(path, usage)
+	if err != nil {
+		return err
+	}
+	return storage.Put(ctx, entry)
+}
+
+// checkAndIncrementQuota enforces role.MaxTokensPerPeriod, returning an
+// error once the role has issued its quota for the current period.
+// Reserving the slot (check + increment) happens under a per-role lock
+// so the reservation is atomic across concurrent callers. Call it before
+// the GitLab API call so a capped role can never hand out more tokens
+// than its quota allows; callers must refundQuota if token issuance
+// ends up failing after the slot was reserved, so a failed attempt
+// doesn't permanently burn a legitimate caller's quota.
+func checkAndIncrementQuota(ctx context.Context, storage logical.Storage, roleName string, role *roleStorageEntry) error {
+	if role.MaxTokensPerPeriod <= 0 {
+		return nil
+	}
+
+	lock := locksutil.LockForKey(quotaLocks, roleName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	path := roleUsagePath(roleName, quotaBucket(time.Now().UTC()))
+	usage, err := getRoleUsage(ctx, storage, path)
+	if err != nil {
+		return err
+	}
+
+	if usage.Count >= role.MaxTokensPerPeriod {
+		return fmt.Errorf("role %q has reached its max_tokens_per_period of %d for the current period", roleName, role.MaxTokensPerPeriod)
+	}
+
+	usage.Count++
+	return putRoleUsage(ctx, storage, path, usage)
+}
+
+// refundQuota releases a slot reserved by checkAndIncrementQuota when the
+// token request it was guarding didn't actually result in an issued
+// token. It's a best-effort decrement under the same per-role lock;
+// errors are swallowed since there is nothing more corrective to do than
+// let the slot stay reserved until the next period.
+func refundQuota(ctx context.Context, storage logical.Storage, roleName string, role *roleStorageEntry) {
+	if role.MaxTokensPerPeriod <= 0 {
+		return
+	}
+
+	lock := locksutil.LockForKey(quotaLocks, roleName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	path := roleUsagePath(roleName, quotaBucket(time.Now().UTC()))
+	usage, err := getRoleUsage(ctx, storage, path)
+	if err != nil || usage.Count <= 0 {
+		return
+	}
+
+	usage.Count--
+	_ = putRoleUsage(ctx, storage, path, usage)
+}
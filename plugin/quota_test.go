@@ -0,0 +1,86 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCheckAndIncrementQuota_ConcurrentRequestsRespectLimit(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	role := &roleStorageEntry{MaxTokensPerPeriod: 1}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var successes int
+
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			if err := checkAndIncrementQuota(ctx, storage, "concurrent-role", role); err == nil {
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if successes != 1 {
+		t.Fatalf("expected exactly 1 of %d concurrent callers to reserve a quota slot with max_tokens_per_period=1, got %d", callers, successes)
+	}
+
+	if err := checkAndIncrementQuota(ctx, storage, "concurrent-role", role); err == nil {
+		t.Fatal("expected quota to already be exhausted for the current period")
+	}
+}
+
+func TestCheckAndIncrementQuota_Unlimited(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	role := &roleStorageEntry{MaxTokensPerPeriod: 0}
+
+	for i := 0; i < 5; i++ {
+		if err := checkAndIncrementQuota(ctx, storage, "unlimited-role", role); err != nil {
+			t.Fatalf("expected no quota enforcement when max_tokens_per_period is unset, got error: %v", err)
+		}
+	}
+}
+
+func TestRefundQuota_FreesAReservedSlot(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	role := &roleStorageEntry{MaxTokensPerPeriod: 1}
+
+	if err := checkAndIncrementQuota(ctx, storage, "refund-role", role); err != nil {
+		t.Fatalf("expected first reservation to succeed, got error: %v", err)
+	}
+	if err := checkAndIncrementQuota(ctx, storage, "refund-role", role); err == nil {
+		t.Fatal("expected quota to be exhausted after the first reservation")
+	}
+
+	refundQuota(ctx, storage, "refund-role", role)
+
+	if err := checkAndIncrementQuota(ctx, storage, "refund-role", role); err != nil {
+		t.Fatalf("expected a refunded slot to be reservable again, got error: %v", err)
+	}
+}
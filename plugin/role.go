@@ -0,0 +1,155 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/tokenutil"
+)
+
+// roleStorageEntry is the pre-defined policy an operator configures at
+// roles/<name>. It mirrors the shape used by other credential backends
+// (e.g. builtin/credential/github) where the role - not the caller -
+// pins down what a generated credential is allowed to look like.
+//
+// TokenTTL/TokenMaxTTL come from the embedded tokenutil.TokenParams and
+// bound the lease of tokens minted against this role, not a Vault
+// service token.
+//
+// DefaultScopes/DefaultAccessLevel are used when the caller omits them;
+// AllowedScopes/DeniedScopes/MaxAccessLevel constrain whatever the
+// caller (or the defaults) end up requesting, so a role can be sealed to
+// one policy and safely delegated to project teams.
+type roleStorageEntry struct {
+	tokenutil.TokenParams
+
+	ProjectID          int      `json:"project_id" structs:"project_id" mapstructure:"project_id"`
+	DefaultScopes      []string `json:"default_scopes" structs:"default_scopes" mapstructure:"default_scopes"`
+	DefaultAccessLevel int      `json:"default_access_level" structs:"default_access_level" mapstructure:"default_access_level"`
+	AllowedScopes      []string `json:"allowed_scopes" structs:"allowed_scopes" mapstructure:"allowed_scopes"`
+	DeniedScopes       []string `json:"denied_scopes" structs:"denied_scopes" mapstructure:"denied_scopes"`
+	MaxAccessLevel     int      `json:"max_access_level" structs:"max_access_level" mapstructure:"max_access_level"`
+	// MaxTokensPerPeriod caps how many tokens this role may issue per
+	// quotaPeriod, enforced via the roles/<name>/usage/<period> counter.
+	MaxTokensPerPeriod int `json:"max_tokens_per_period" structs:"max_tokens_per_period" mapstructure:"max_tokens_per_period"`
+}
+
+// roleSchema is the field map for roles/<name>. token_ttl/token_max_ttl
+// are added via tokenutil so the role gets Vault's standard TTL parsing
+// and validation instead of us reinventing it.
+var roleSchema = map[string]*framework.FieldSchema{
+	"name": {
+		Type:        framework.TypeString,
+		Description: "Name of the role",
+	},
+	"project_id": {
+		Type:        framework.TypeInt,
+		Description: "Project ID to create project access tokens for",
+	},
+	"default_scopes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "Scopes used for tokens issued against this role when the caller doesn't supply any",
+	},
+	"default_access_level": {
+		Type:        framework.TypeInt,
+		Description: "Access level used for tokens issued against this role when the caller doesn't supply one",
+	},
+	"allowed_scopes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "If set, the only scopes a caller may request against this role",
+	},
+	"denied_scopes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "Scopes a caller may never request against this role, even if also in allowed_scopes",
+	},
+	"max_access_level": {
+		Type:        framework.TypeInt,
+		Description: "If set, the highest access_level a caller may request against this role",
+	},
+	"max_tokens_per_period": {
+		Type:        framework.TypeInt,
+		Description: "If set, the maximum number of tokens this role may issue per quota period",
+	},
+}
+
+func init() {
+	tokenutil.AddTokenFields(roleSchema)
+}
+
+func (r *roleStorageEntry) toResponseData() map[string]interface{} {
+	d := map[string]interface{}{
+		"project_id":            r.ProjectID,
+		"default_scopes":        r.DefaultScopes,
+		"default_access_level":  r.DefaultAccessLevel,
+		"allowed_scopes":        r.AllowedScopes,
+		"denied_scopes":         r.DeniedScopes,
+		"max_access_level":      r.MaxAccessLevel,
+		"max_tokens_per_period": r.MaxTokensPerPeriod,
+	}
+	r.PopulateTokenData(d)
+	return d
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// constrain fills in base's scopes/access_level from the role's defaults
+// when the caller omitted them, then rejects anything the role doesn't
+// permit.
+func (r *roleStorageEntry) constrain(base *BaseTokenStorageEntry) error {
+	if len(base.Scopes) == 0 {
+		base.Scopes = r.DefaultScopes
+	}
+	if base.AccessLevel == 0 {
+		base.AccessLevel = r.DefaultAccessLevel
+	}
+
+	var err *multierror.Error
+	for _, scope := range base.Scopes {
+		if len(r.AllowedScopes) > 0 && !containsString(r.AllowedScopes, scope) {
+			err = multierror.Append(err, fmt.Errorf("scope %q is not in the role's allowed_scopes", scope))
+		}
+		if containsString(r.DeniedScopes, scope) {
+			err = multierror.Append(err, fmt.Errorf("scope %q is denied by the role's denied_scopes", scope))
+		}
+	}
+
+	if r.MaxAccessLevel > 0 && base.AccessLevel > r.MaxAccessLevel {
+		err = multierror.Append(err, fmt.Errorf("access_level %d exceeds the role's max_access_level of %d", base.AccessLevel, r.MaxAccessLevel))
+	}
+
+	return err.ErrorOrNil()
+}
+
+// validateDefaults checks the role's own default_scopes/default_access_level
+// against its own allowed_scopes/denied_scopes/max_access_level, so a role
+// can't be written in a self-contradictory state where every token/<role>
+// request using the defaults is guaranteed to fail constrain's checks.
+func (r *roleStorageEntry) validateDefaults() error {
+	base := BaseTokenStorageEntry{
+		Scopes:      r.DefaultScopes,
+		AccessLevel: r.DefaultAccessLevel,
+	}
+	return r.constrain(&base)
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import "testing"
+
+func TestConstrain_FillsInDefaultsWhenCallerOmitsThem(t *testing.T) {
+	r := &roleStorageEntry{
+		DefaultScopes:      []string{"read_api"},
+		DefaultAccessLevel: 20,
+	}
+	base := &BaseTokenStorageEntry{}
+
+	if err := r.constrain(base); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(base.Scopes) != 1 || base.Scopes[0] != "read_api" {
+		t.Fatalf("expected default scopes to be filled in, got %v", base.Scopes)
+	}
+	if base.AccessLevel != 20 {
+		t.Fatalf("expected default access level to be filled in, got %d", base.AccessLevel)
+	}
+}
+
+func TestConstrain_RejectsScopeNotInAllowedScopes(t *testing.T) {
+	r := &roleStorageEntry{AllowedScopes: []string{"read_api"}}
+	base := &BaseTokenStorageEntry{Scopes: []string{"api"}}
+
+	if err := r.constrain(base); err == nil {
+		t.Fatal("expected an error for a scope outside allowed_scopes")
+	}
+}
+
+func TestConstrain_RejectsDeniedScopeEvenIfAllowed(t *testing.T) {
+	r := &roleStorageEntry{AllowedScopes: []string{"api"}, DeniedScopes: []string{"api"}}
+	base := &BaseTokenStorageEntry{Scopes: []string{"api"}}
+
+	if err := r.constrain(base); err == nil {
+		t.Fatal("expected denied_scopes to win even when the scope is also allowed")
+	}
+}
+
+func TestConstrain_RejectsAccessLevelAboveMax(t *testing.T) {
+	r := &roleStorageEntry{MaxAccessLevel: 30}
+	base := &BaseTokenStorageEntry{Scopes: []string{"api"}, AccessLevel: 40}
+
+	if err := r.constrain(base); err == nil {
+		t.Fatal("expected an error for an access_level above max_access_level")
+	}
+}
+
+func TestValidateDefaults_RejectsContradictoryRole(t *testing.T) {
+	r := &roleStorageEntry{
+		DefaultAccessLevel: 40,
+		MaxAccessLevel:     30,
+	}
+
+	if err := r.validateDefaults(); err == nil {
+		t.Fatal("expected an error when default_access_level exceeds the role's own max_access_level")
+	}
+}
+
+func TestValidateDefaults_AcceptsConsistentRole(t *testing.T) {
+	r := &roleStorageEntry{
+		DefaultScopes:      []string{"read_api"},
+		DefaultAccessLevel: 20,
+		AllowedScopes:      []string{"read_api"},
+		MaxAccessLevel:     30,
+	}
+
+	if err := r.validateDefaults(); err != nil {
+		t.Fatalf("expected a consistent role's defaults to validate cleanly, got: %v", err)
+	}
+}
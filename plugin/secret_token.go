@@ -0,0 +1,142 @@
+// Copyright 2021 Splunk Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlabtoken
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// secretAccessTokenType is the Secret type name used for project/group
+// access tokens so Revoke/Renew can be wired through Vault's lease
+// manager instead of the caller having to track expiry themselves.
+const secretAccessTokenType = "gitlab_access_token"
+
+// secretToken internal data keys.
+const (
+	secretTokenIDKey   = "token_id"
+	secretProjectIDKey = "project_id"
+	secretGroupIDKey   = "group_id"
+	secretRoleNameKey  = "role_name"
+	secretTokenKindKey = "kind"
+	// secretExpiresAtKey is the real expires_at GitLab set on the token
+	// at mint time (RFC3339), stored so Renew can refuse to extend a
+	// Vault lease past the point where the underlying GitLab token is
+	// already dead.
+	secretExpiresAtKey = "expires_at"
+)
+
+func secretToken(b *GitlabBackend) *framework.Secret {
+	return &framework.Secret{
+		Type: secretAccessTokenType,
+		Fields: map[string]*framework.FieldSchema{
+			"token": {
+				Type:        framework.TypeString,
+				Description: "Access token",
+			},
+		},
+		Renew:  b.secretTokenRenew,
+		Revoke: b.secretTokenRevoke,
+	}
+}
+
+func (b *GitlabBackend) secretTokenRenew(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleNameRaw, ok := req.Secret.InternalData[secretRoleNameKey]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing %s internal data", secretRoleNameKey)
+	}
+
+	role, err := b.roleEntry(ctx, req.Storage, roleNameRaw.(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %q no longer exists", roleNameRaw.(string))
+	}
+
+	expiresAtRaw, ok := req.Secret.InternalData[secretExpiresAtKey]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing %s internal data", secretExpiresAtKey)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, fmt.Sprintf("%v", expiresAtRaw))
+	if err != nil {
+		return nil, fmt.Errorf("secret has an unparseable %s internal data value: %w", secretExpiresAtKey, err)
+	}
+
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		return nil, fmt.Errorf("the underlying GitLab access token expired at %s; renew is no longer possible, issue a new token", expiresAt)
+	}
+
+	// Never hand back a lease that outlives the actual GitLab token -
+	// the role's configured windows are a ceiling, not a guarantee that
+	// the credential is still alive for that long.
+	ttl := role.TokenTTL
+	if remaining < ttl {
+		ttl = remaining
+	}
+	maxTTL := role.TokenMaxTTL
+	if remaining < maxTTL {
+		maxTTL = remaining
+	}
+
+	resp := &logical.Response{Secret: req.Secret}
+	resp.Secret.TTL = ttl
+	resp.Secret.MaxTTL = maxTTL
+	return resp, nil
+}
+
+func (b *GitlabBackend) secretTokenRevoke(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	tokenIDRaw, ok := req.Secret.InternalData[secretTokenIDKey]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing %s internal data", secretTokenIDKey)
+	}
+	tokenID := int(tokenIDRaw.(float64))
+
+	kind := TokenKindProject
+	if kindRaw, ok := req.Secret.InternalData[secretTokenKindKey]; ok {
+		kind = TokenKind(fmt.Sprintf("%v", kindRaw))
+	}
+
+	gc, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain gitlab client - %w", err)
+	}
+
+	switch kind {
+	case TokenKindGroup:
+		groupIDRaw, ok := req.Secret.InternalData[secretGroupIDKey]
+		if !ok {
+			return nil, fmt.Errorf("secret is missing %s internal data", secretGroupIDKey)
+		}
+		if err := gc.RevokeGroupAccessToken(int(groupIDRaw.(float64)), tokenID); err != nil {
+			return nil, fmt.Errorf("failed to revoke group access token - %w", err)
+		}
+	default:
+		projectIDRaw, ok := req.Secret.InternalData[secretProjectIDKey]
+		if !ok {
+			return nil, fmt.Errorf("secret is missing %s internal data", secretProjectIDKey)
+		}
+		if err := gc.RevokeProjectAccessToken(int(projectIDRaw.(float64)), tokenID); err != nil {
+			return nil, fmt.Errorf("failed to revoke project access token - %w", err)
+		}
+	}
+
+	return nil, nil
+}
@@ -25,17 +25,32 @@ import (
 
 var errInvalidAccessLevel = errors.New("invalid access level")
 
+// TokenKind distinguishes a GitLab project access token from a group
+// access token so BaseTokenStorageEntry can back either path instead of
+// duplicating the struct per token kind.
+type TokenKind string
+
+const (
+	TokenKindProject TokenKind = "project"
+	TokenKindGroup   TokenKind = "group"
+)
+
 type TokenStorageEntry struct {
 	BaseTokenStorage BaseTokenStorageEntry
 	ExpiresAt        *time.Time `json:"expires_at" structs:"expires_at" mapstructure:"expires_at,omitempty"`
+	// RoleName is set when an ad-hoc token request is bound to a role via
+	// its "role" field; it keys that role's quota counter and is reported
+	// in the response's audit metadata.
+	RoleName string `json:"role_name" structs:"role_name" mapstructure:"role_name,omitempty"`
 }
 
 type BaseTokenStorageEntry struct {
 	// `json:"" structs:"" mapstructure:""`
-	ID          int      `json:"id" structs:"id" mapstructure:"id"`
-	Name        string   `json:"name" structs:"name" mapstructure:"name"`
-	Scopes      []string `json:"scopes" structs:"scopes" mapstructure:"scopes"`
-	AccessLevel int      `json:"access_level" structs:"access_level" mapstructure:"access_level,omitempty"`
+	ID          int       `json:"id" structs:"id" mapstructure:"id"`
+	Name        string    `json:"name" structs:"name" mapstructure:"name"`
+	Scopes      []string  `json:"scopes" structs:"scopes" mapstructure:"scopes"`
+	AccessLevel int       `json:"access_level" structs:"access_level" mapstructure:"access_level,omitempty"`
+	Kind        TokenKind `json:"kind" structs:"kind" mapstructure:"kind,omitempty"`
 }
 
 func (tokenStorage *TokenStorageEntry) assertValid(maxTTL time.Duration, allowOwnerLevel bool) error {
@@ -66,7 +81,7 @@ func (baseTokenStorage *BaseTokenStorageEntry) assertValid(allowOwnerLevel bool)
 	}
 	if len(baseTokenStorage.Scopes) == 0 {
 		err = multierror.Append(err, errors.New("scopes are empty"))
-	} else if e := validateScopes(baseTokenStorage.Scopes); e != nil {
+	} else if e := baseTokenStorage.validateScopesForKind(); e != nil {
 		err = multierror.Append(err, e)
 	}
 
@@ -86,6 +101,16 @@ func (baseTokenStorage *BaseTokenStorageEntry) assertValid(allowOwnerLevel bool)
 	return err.ErrorOrNil()
 }
 
+// validateScopesForKind dispatches to the scope set for the token's Kind. It
+// defaults to project scopes so existing callers that never set Kind
+// keep their current behavior.
+func (baseTokenStorage *BaseTokenStorageEntry) validateScopesForKind() error {
+	if baseTokenStorage.Kind == TokenKindGroup {
+		return validateGroupScopes(baseTokenStorage.Scopes)
+	}
+	return validateScopes(baseTokenStorage.Scopes)
+}
+
 func (tokenStorage *TokenStorageEntry) retrieve(data *framework.FieldData) {
 	tokenStorage.BaseTokenStorage.retrieve(data)
 	if expiresAtRaw, ok := data.GetOk("expires_at"); ok {